@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -40,20 +43,24 @@ const (
 // The migrationController controller migrates resources to a new write key
 // and annotated the write key secret afterwards with the migrated GRs. It
 //
-// * watches pods and secrets in <operand-target-namespace>
-// * watches secrets in openshift-config-manager
-// * computes a new, desired encryption config from encryption-config-<revision>
-//   and the existing keys in openshift-config-managed.
-// * compares desired with current target config and stops when they differ
-// * checks the write-key secret whether
+//   - watches pods and secrets in <operand-target-namespace>
+//   - watches secrets in openshift-config-manager
+//   - computes a new, desired encryption config from encryption-config-<revision>
+//     and the existing keys in openshift-config-managed.
+//   - compares desired with current target config and stops when they differ
+//   - checks the write-key secret whether
 //   - encryption.apiserver.operator.openshift.io/migrated-timestamp annotation
 //     is missing or
 //   - a write-key for a resource does not show up in the
 //     encryption.apiserver.operator.openshift.io/migrated-resources And then
-//     starts a migration job (currently in-place synchronously, soon with the upstream migration tool)
-// * updates the encryption.apiserver.operator.openshift.io/migrated-timestamp and
-//   encryption.apiserver.operator.openshift.io/migrated-resources annotations on the
-//   current write-key secrets.
+//     starts a migration job through the migrators.Migrator passed to
+//     NewMigrationController (e.g. migrators.NewInProcessMigrator to migrate in-place, or
+//     migrators.NewStorageVersionMigrator to drive migration through
+//     StorageVersionMigration resources reconciled by the upstream
+//     kube-storage-version-migrator)
+//   - updates the encryption.apiserver.operator.openshift.io/migrated-timestamp and
+//     encryption.apiserver.operator.openshift.io/migrated-resources annotations on the
+//     current write-key secrets.
 type migrationController struct {
 	component string
 	name      string
@@ -68,6 +75,29 @@ type migrationController struct {
 	migrator                 migrators.Migrator
 	provider                 Provider
 	preconditionsFulfilledFn preconditionsFulfilled
+
+	// maxConcurrentMigrations bounds how many GRs migrateKeysIfNeededAndRevisionStable will
+	// drive to completion at once; see WithMaxConcurrentMigrations.
+	maxConcurrentMigrations int
+
+	// trackingMu guards tracking, which dedupes the started/completed metrics recorded by
+	// recordMigrationMetrics against repeated observations of the same migration attempt.
+	trackingMu sync.Mutex
+	tracking   map[schema.GroupResource]*migrationTracking
+}
+
+// MigrationControllerOption allows opting into optional migrationController behavior that
+// callers should not have to thread through the main constructor signature.
+type MigrationControllerOption func(*migrationController)
+
+// WithMaxConcurrentMigrations bounds how many GroupResources the controller will drive
+// migrations for at the same time, instead of the default of one at a time. The limit is
+// shared across all GRs in a sync so a single large or noisy resource cannot delay the rest.
+// n <= 0 means unbounded, matching migrators.WithMaxConcurrentMigrations.
+func WithMaxConcurrentMigrations(n int) MigrationControllerOption {
+	return func(c *migrationController) {
+		c.maxConcurrentMigrations = n
+	}
 }
 
 func NewMigrationController(
@@ -82,6 +112,7 @@ func NewMigrationController(
 	secretClient corev1client.SecretsGetter,
 	encryptionSecretSelector metav1.ListOptions,
 	eventRecorder events.Recorder,
+	opts ...MigrationControllerOption,
 ) factory.Controller {
 	c := &migrationController{
 		component:      component,
@@ -94,10 +125,14 @@ func NewMigrationController(
 		migrator:                 migrator,
 		provider:                 provider,
 		preconditionsFulfilledFn: preconditionsFulfilledFn,
+		maxConcurrentMigrations:  1,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
 
 	return factory.New().ResyncEvery(time.Minute).WithSync(c.sync).WithInformers(
-		migrator,
+		c.migrator,
 		operatorClient.Informer(),
 		kubeInformersForNamespaces.InformersFor("openshift-config-managed").Core().V1().Secrets().Informer(),
 		apiServerConfigInformer.Informer(), // do not remove, used by the precondition checker
@@ -138,11 +173,26 @@ func (c *migrationController) sync(ctx context.Context, syncCtx factory.SyncCont
 	if len(migratingResources) > 0 {
 		progressingCondition.Status = operatorv1.ConditionTrue
 		progressingCondition.Reason = "Migrating"
-		progressingCondition.Message = fmt.Sprintf("migrating resources to a new write key: %v", grsToHumanReadable(migratingResources))
+		progressingCondition.Message = fmt.Sprintf("migrating resources to a new write key: %v", c.migratingResourcesToHumanReadable(migratingResources))
 	}
 	return migrationError
 }
 
+// migratingResourcesToHumanReadable renders each gr as "<group>/<resource>", appending a
+// (processed/total) progress count when the migrator can report one, so that forward progress
+// stays visible across apiserver restarts instead of just "still migrating".
+func (c *migrationController) migratingResourcesToHumanReadable(grs []schema.GroupResource) []string {
+	ret := make([]string, 0, len(grs))
+	for _, gr := range grs {
+		s := fmt.Sprintf("%s/%s", groupToHumanReadable(gr), gr.Resource)
+		if processed, total, ok := c.migrator.Progress(gr); ok && total > 0 {
+			s = fmt.Sprintf("%s (%d/%d)", s, processed, total)
+		}
+		ret = append(ret, s)
+	}
+	return ret
+}
+
 // TODO doc
 func (c *migrationController) migrateKeysIfNeededAndRevisionStable(ctx context.Context, syncContext factory.SyncContext, encryptedGRs []schema.GroupResource) (migratingResources []schema.GroupResource, err error) {
 	// no storage migration during revision changes
@@ -190,47 +240,37 @@ func (c *migrationController) migrateKeysIfNeededAndRevisionStable(ctx context.C
 	// we never want to migrate during an intermediate state because that could lead to one API server
 	// using a write key that another API server has not observed
 	// this could lead to etcd storing data that not all API servers can decrypt
-	var errs []error
-	for _, gr := range grs {
-		grActualKeys := currentState[gr]
-		if !grActualKeys.HasWriteKey() {
-			continue // no write key to migrate to
-		}
 
-		if alreadyMigrated, _, _ := state.MigratedFor([]schema.GroupResource{gr}, grActualKeys.WriteKey); alreadyMigrated {
-			continue
-		}
+	// migrateOne drives the migration of a single GR to its current write key to completion,
+	// including the secret annotation update once the migration succeeds. It returns whether
+	// the migration is still running, whether the returned error (if any) is a terminal
+	// migration result as opposed to a transient controller-side error, and that error.
+	migrateOne := func(gr schema.GroupResource) (migrating, terminal bool, err error) {
+		grActualKeys := currentState[gr]
 
 		// idem-potent migration start
 		finished, result, when, err := c.migrator.EnsureMigration(gr, grActualKeys.WriteKey.Key.Name)
 		if err == nil && finished && result != nil && time.Since(when) > migrationRetryDuration {
 			// last migration error is far enough ago. Prune and retry.
 			if err := c.migrator.PruneMigration(gr); err != nil {
-				errs = append(errs, err)
-				continue
+				return false, false, err
 			}
 			finished, result, when, err = c.migrator.EnsureMigration(gr, grActualKeys.WriteKey.Key.Name)
-
 		}
 		if err != nil {
-			errs = append(errs, err)
-			continue
+			return false, false, err
 		}
 		if finished && result != nil {
-			errs = append(errs, result)
-			continue
+			return false, true, result
 		}
-
 		if !finished {
-			migratingResources = append(migratingResources, gr)
-			continue
+			return true, false, nil
 		}
 
 		// update secret annotations
 		oldWriteKey, err := secrets.FromKeyState(c.component, grActualKeys.WriteKey)
 		if err != nil {
-			errs = append(errs, result)
-			continue
+			return false, false, err
 		}
 		if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
 			s, err := c.secretClient.Secrets(oldWriteKey.Namespace).Get(ctx, oldWriteKey.Name, metav1.GetOptions{})
@@ -249,9 +289,79 @@ func (c *migrationController) migrateKeysIfNeededAndRevisionStable(ctx context.C
 			_, _, updateErr := resourceapply.ApplySecret(ctx, c.secretClient, syncContext.Recorder(), s)
 			return updateErr
 		}); err != nil {
-			errs = append(errs, err)
+			return false, false, err
+		}
+
+		return false, true, nil
+	}
+
+	// up to c.maxConcurrentMigrations GRs are driven at once, sharing one semaphore so a
+	// single large or noisy resource cannot starve the others of migration progress.
+	// maxConcurrentMigrations <= 0 means unbounded, so sem stays nil and is never blocked on.
+	var (
+		mu         sync.Mutex
+		errs       []error
+		wg         sync.WaitGroup
+		conditions []v1helpers.UpdateStatusFunc
+		sem        chan struct{}
+	)
+	if c.maxConcurrentMigrations > 0 {
+		sem = make(chan struct{}, c.maxConcurrentMigrations)
+	}
+	for _, gr := range grs {
+		grActualKeys := currentState[gr]
+		if !grActualKeys.HasWriteKey() {
+			continue // no write key to migrate to
+		}
+
+		if alreadyMigrated, _, _ := state.MigratedFor([]schema.GroupResource{gr}, grActualKeys.WriteKey); alreadyMigrated {
+			// clear any stale per-GR conditions left over from a prior, now-finished migration
+			mu.Lock()
+			conditions = append(conditions, grConditionUpdates(gr, false, false, "")...)
+			mu.Unlock()
 			continue
 		}
+
+		wg.Add(1)
+		if sem != nil {
+			sem <- struct{}{}
+		}
+		go func(gr schema.GroupResource) {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+
+			migrating, terminal, err := migrateOne(gr)
+			c.recordMigrationMetrics(gr, migrating, terminal, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				conditions = append(conditions, grConditionUpdates(gr, false, true, err.Error())...)
+			} else {
+				conditions = append(conditions, grConditionUpdates(gr, migrating, false, "")...)
+			}
+			if migrating {
+				migratingResources = append(migratingResources, gr)
+			}
+		}(gr)
+	}
+	wg.Wait()
+
+	// goroutines finish in any order; restore gr order so condition strings stay deterministic
+	sort.Slice(migratingResources, func(i, j int) bool {
+		return migratingResources[i].String() < migratingResources[j].String()
+	})
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+
+	if len(conditions) > 0 {
+		if _, _, updateErr := operatorv1helpers.UpdateStatus(ctx, c.operatorClient, conditions...); updateErr != nil {
+			errs = append(errs, updateErr)
+		}
 	}
 
 	return migratingResources, errors.NewAggregate(errs)
@@ -306,10 +416,110 @@ func groupToHumanReadable(gr schema.GroupResource) string {
 	return group
 }
 
-func grsToHumanReadable(grs []schema.GroupResource) []string {
-	ret := make([]string, 0, len(grs))
-	for _, gr := range grs {
-		ret = append(ret, fmt.Sprintf("%s/%s", groupToHumanReadable(gr), gr.Resource))
+// migrationConditionPrefix builds the per-GR condition type prefix, e.g. "apps"/"deployments"
+// becomes "EncryptionMigrationAppsDeployments", so operators can see which specific GR a
+// Progressing/Degraded condition is about instead of only the aggregate condition.
+func migrationConditionPrefix(gr schema.GroupResource) string {
+	return "EncryptionMigration" + toPascalCase(groupToHumanReadable(gr)) + toPascalCase(gr.Resource)
+}
+
+// toPascalCase capitalizes the first letter of each run of letters/digits in s and drops every
+// other character, so strings like "config.openshift.io" become usable as part of a condition
+// type: "ConfigOpenshiftIo".
+func toPascalCase(s string) string {
+	var b strings.Builder
+	capNext := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			capNext = true
+			continue
+		}
+		if capNext {
+			b.WriteRune(unicode.ToUpper(r))
+			capNext = false
+		} else {
+			b.WriteRune(r)
+		}
 	}
-	return ret
+	return b.String()
+}
+
+// grConditionUpdates returns the Progressing/Degraded condition updates for gr. degradedMessage
+// is only used when degraded is true.
+func grConditionUpdates(gr schema.GroupResource, progressing, degraded bool, degradedMessage string) []v1helpers.UpdateStatusFunc {
+	prefix := migrationConditionPrefix(gr)
+
+	progressingCondition := operatorv1.OperatorCondition{Type: prefix + "Progressing", Status: operatorv1.ConditionFalse}
+	if progressing {
+		progressingCondition.Status = operatorv1.ConditionTrue
+		progressingCondition.Reason = "Migrating"
+		progressingCondition.Message = fmt.Sprintf("migrating %s to a new write key", gr)
+	}
+
+	degradedCondition := operatorv1.OperatorCondition{Type: prefix + "Degraded", Status: operatorv1.ConditionFalse}
+	if degraded {
+		degradedCondition.Status = operatorv1.ConditionTrue
+		degradedCondition.Reason = "Error"
+		degradedCondition.Message = degradedMessage
+	}
+
+	return []v1helpers.UpdateStatusFunc{
+		v1helpers.UpdateConditionFn(progressingCondition),
+		v1helpers.UpdateConditionFn(degradedCondition),
+	}
+}
+
+// recordMigrationMetrics tracks per-GR started/completed counters, migration duration and
+// objects-remaining, fed by c.migrator.Progress. Started/completed are deduped against the
+// previous observation for gr so that repeatedly polling an in-flight or already-terminal
+// migration does not inflate the counters.
+// terminal indicates this observation is a genuine migration end-state (success or failure),
+// as opposed to a transient error encountered while starting or polling the migration.
+func (c *migrationController) recordMigrationMetrics(gr schema.GroupResource, migrating, terminal bool, err error) {
+	label := migrationMetricLabel(gr)
+
+	c.trackingMu.Lock()
+	defer c.trackingMu.Unlock()
+	if c.tracking == nil {
+		c.tracking = map[schema.GroupResource]*migrationTracking{}
+	}
+	t, ok := c.tracking[gr]
+	if !ok {
+		t = &migrationTracking{}
+		c.tracking[gr] = t
+	}
+
+	switch {
+	case migrating:
+		if t.startedAt.IsZero() {
+			t.startedAt = time.Now()
+			migrationStartedTotal.WithLabelValues(label).Inc()
+		}
+		t.completed = false
+	case terminal:
+		if !t.completed {
+			if !t.startedAt.IsZero() {
+				migrationDurationSeconds.WithLabelValues(label).Observe(time.Since(t.startedAt).Seconds())
+			}
+			result := "success"
+			if err != nil {
+				result = "failure"
+			}
+			migrationCompletedTotal.WithLabelValues(label, result).Inc()
+			t.completed = true
+		}
+		t.startedAt = time.Time{}
+	}
+
+	if processed, total, ok := c.migrator.Progress(gr); ok {
+		migrationObjectsRemaining.WithLabelValues(label).Set(float64(total - processed))
+	}
+}
+
+type migrationTracking struct {
+	startedAt time.Time
+	// completed guards migrationCompletedTotal/migrationDurationSeconds against being recorded
+	// more than once for the same terminal result while it is repeatedly observed (e.g. during
+	// migrationRetryDuration's cooldown before a failed migration is pruned and retried).
+	completed bool
 }
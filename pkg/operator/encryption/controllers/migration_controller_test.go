@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/encryption/controllers/migrators"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+)
+
+func TestToPascalCase(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"secrets", "Secrets"},
+		{"config.openshift.io", "ConfigOpenshiftIo"},
+		{"a--b", "AB"},
+		{"4chan", "4chan"},
+	}
+	for _, tt := range tests {
+		if got := toPascalCase(tt.in); got != tt.want {
+			t.Errorf("toPascalCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestMigrationConditionPrefixCanCollide documents a known limitation of
+// migrationConditionPrefix: because it concatenates the PascalCased group and resource with no
+// separator, a GroupResource with no group and a compound resource name can collide with an
+// unrelated GroupResource whose group/resource split lands on the same boundary, e.g.
+// {Group: "", Resource: "FooBar"} (whose empty group is rendered as "Core") and
+// {Group: "CoreFoo", Resource: "Bar"} both produce "EncryptionMigrationCoreFooBar". This is not
+// exercised by real API GroupResources (group and resource names don't contain uppercase
+// letters), but is worth pinning down so a future change to either side of the split doesn't
+// silently introduce an observable collision.
+func TestMigrationConditionPrefixCanCollide(t *testing.T) {
+	a := migrationConditionPrefix(schema.GroupResource{Resource: "FooBar"})
+	b := migrationConditionPrefix(schema.GroupResource{Group: "CoreFoo", Resource: "Bar"})
+	if a != b {
+		t.Fatalf("expected the documented collision to still reproduce: %q != %q", a, b)
+	}
+}
+
+func TestMigrationConditionPrefix(t *testing.T) {
+	tests := []struct {
+		gr   schema.GroupResource
+		want string
+	}{
+		{schema.GroupResource{Resource: "secrets"}, "EncryptionMigrationCoreSecrets"},
+		{schema.GroupResource{Group: "apps", Resource: "deployments"}, "EncryptionMigrationAppsDeployments"},
+	}
+	for _, tt := range tests {
+		if got := migrationConditionPrefix(tt.gr); got != tt.want {
+			t.Errorf("migrationConditionPrefix(%v) = %q, want %q", tt.gr, got, tt.want)
+		}
+	}
+}
+
+func TestGRConditionUpdates(t *testing.T) {
+	gr := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	prefix := "EncryptionMigrationAppsDeployments"
+
+	updates := grConditionUpdates(gr, true, false, "")
+	if len(updates) != 2 {
+		t.Fatalf("len(updates) = %d, want 2", len(updates))
+	}
+	status := applyConditionUpdates(t, updates)
+	progressing := findCondition(status, prefix+"Progressing")
+	degraded := findCondition(status, prefix+"Degraded")
+	if progressing == nil || progressing.Status != operatorv1.ConditionTrue {
+		t.Errorf("progressing condition = %+v, want Status=True", progressing)
+	}
+	if degraded == nil || degraded.Status != operatorv1.ConditionFalse {
+		t.Errorf("degraded condition = %+v, want Status=False", degraded)
+	}
+
+	status = applyConditionUpdates(t, grConditionUpdates(gr, false, true, "boom"))
+	progressing = findCondition(status, prefix+"Progressing")
+	degraded = findCondition(status, prefix+"Degraded")
+	if progressing == nil || progressing.Status != operatorv1.ConditionFalse {
+		t.Errorf("progressing condition = %+v, want Status=False", progressing)
+	}
+	if degraded == nil || degraded.Status != operatorv1.ConditionTrue || degraded.Message != "boom" {
+		t.Errorf("degraded condition = %+v, want Status=True, Message=boom", degraded)
+	}
+}
+
+// applyConditionUpdates runs each v1helpers.UpdateStatusFunc against a fresh OperatorStatus and
+// returns the resulting conditions, mirroring how operatorv1helpers.UpdateStatus applies them.
+func applyConditionUpdates(t *testing.T, updates []v1helpers.UpdateStatusFunc) *operatorv1.OperatorStatus {
+	t.Helper()
+	status := &operatorv1.OperatorStatus{}
+	for _, update := range updates {
+		if err := update(status); err != nil {
+			t.Fatalf("UpdateStatusFunc: %v", err)
+		}
+	}
+	return status
+}
+
+func findCondition(status *operatorv1.OperatorStatus, conditionType string) *operatorv1.OperatorCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+type fakeMigrator struct {
+	cache.Controller
+	processed, total int64
+	ok               bool
+}
+
+func (f *fakeMigrator) EnsureMigration(gr schema.GroupResource, writeKey string) (bool, error, time.Time, error) {
+	return false, nil, time.Time{}, nil
+}
+
+func (f *fakeMigrator) PruneMigration(gr schema.GroupResource) error { return nil }
+
+func (f *fakeMigrator) Progress(gr schema.GroupResource) (processed, total int64, ok bool) {
+	return f.processed, f.total, f.ok
+}
+
+var _ migrators.Migrator = &fakeMigrator{}
+
+func TestRecordMigrationMetricsDedupesStartedAndCompleted(t *testing.T) {
+	gr := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	label := migrationMetricLabel(gr)
+	c := &migrationController{migrator: &fakeMigrator{processed: 3, total: 10, ok: true}}
+
+	startedBefore := testutil.ToFloat64(migrationStartedTotal.WithLabelValues(label))
+	completedSuccessBefore := testutil.ToFloat64(migrationCompletedTotal.WithLabelValues(label, "success"))
+
+	// repeated "still migrating" observations must only count as one start.
+	c.recordMigrationMetrics(gr, true, false, nil)
+	c.recordMigrationMetrics(gr, true, false, nil)
+	c.recordMigrationMetrics(gr, true, false, nil)
+	if got := testutil.ToFloat64(migrationStartedTotal.WithLabelValues(label)); got != startedBefore+1 {
+		t.Fatalf("migrationStartedTotal = %v, want %v", got, startedBefore+1)
+	}
+
+	// repeated terminal observations during migrationRetryDuration's cooldown must only
+	// count as one completion.
+	c.recordMigrationMetrics(gr, false, true, nil)
+	c.recordMigrationMetrics(gr, false, true, nil)
+	c.recordMigrationMetrics(gr, false, true, nil)
+	if got := testutil.ToFloat64(migrationCompletedTotal.WithLabelValues(label, "success")); got != completedSuccessBefore+1 {
+		t.Fatalf("migrationCompletedTotal{success} = %v, want %v", got, completedSuccessBefore+1)
+	}
+
+	if got, _, ok := c.migrator.Progress(gr); !ok || got != 3 {
+		t.Fatalf("unexpected Progress readback: %d, %v", got, ok)
+	}
+	if got := testutil.ToFloat64(migrationObjectsRemaining.WithLabelValues(label)); got != 7 {
+		t.Fatalf("migrationObjectsRemaining = %v, want 7", got)
+	}
+
+	// a retry (migrating again) must re-arm the completed dedup so the next terminal
+	// observation is counted.
+	c.recordMigrationMetrics(gr, true, false, nil)
+	c.recordMigrationMetrics(gr, false, true, errFake)
+	if got := testutil.ToFloat64(migrationCompletedTotal.WithLabelValues(label, "failure")); got != 1 {
+		t.Fatalf("migrationCompletedTotal{failure} = %v, want 1", got)
+	}
+}
+
+var errFake = fakeErr("boom")
+
+type fakeErr string
+
+func (e fakeErr) Error() string { return string(e) }
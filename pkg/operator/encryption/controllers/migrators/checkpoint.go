@@ -0,0 +1,127 @@
+package migrators
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// checkpointConfigMapName holds one data key per GroupResource and write key currently being
+// migrated in-process (see checkpointKey), so a restarted operator can resume a migration instead of re-listing the world.
+const checkpointConfigMapName = "encryption-migration-progress"
+
+// checkpoint is the serialized, per-GR progress of an in-process migration.
+type checkpoint struct {
+	// Continue is the list continuation token for the next page to migrate. Empty means
+	// either the migration has not started, or it finished listing (see Processed/Total).
+	Continue string `json:"continue,omitempty"`
+	// ResourceVersion pins the list to the snapshot the migration started from, so that
+	// resuming with Continue observes a consistent view.
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	Processed       int64  `json:"processed"`
+	Total           int64  `json:"total"`
+}
+
+// checkpointStore persists per-GR migration checkpoints in a ConfigMap, keyed by checkpointKey
+// (the GR together with the write key), so an in-process migration can resume after an apiserver restart
+// instead of starting over.
+type checkpointStore struct {
+	configMapClient corev1client.ConfigMapsGetter
+	namespace       string
+}
+
+func newCheckpointStore(configMapClient corev1client.ConfigMapsGetter, namespace string) *checkpointStore {
+	return &checkpointStore{configMapClient: configMapClient, namespace: namespace}
+}
+
+// groupResourceKey renders gr as "resource.group" (or just "resource" for the core group), used
+// both as the basis of checkpointKey and as a metric label (see metricGR).
+func groupResourceKey(gr schema.GroupResource) string {
+	if len(gr.Group) == 0 {
+		return gr.Resource
+	}
+	return fmt.Sprintf("%s.%s", gr.Resource, gr.Group)
+}
+
+// checkpointKey derives the ConfigMap data key for gr/writeKey, mirroring migrationName: keying
+// on the write key as well as the GR means rotating the write key starts a fresh checkpoint
+// instead of resuming the previous key's stale progress.
+func checkpointKey(gr schema.GroupResource, writeKey string) string {
+	return fmt.Sprintf("%s-%s", groupResourceKey(gr), writeKey)
+}
+
+func (s *checkpointStore) load(ctx context.Context, gr schema.GroupResource, writeKey string) (checkpoint, error) {
+	cm, err := s.configMapClient.ConfigMaps(s.namespace).Get(ctx, checkpointConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return checkpoint{}, nil
+	}
+	if err != nil {
+		return checkpoint{}, err
+	}
+
+	raw, ok := cm.Data[checkpointKey(gr, writeKey)]
+	if !ok {
+		return checkpoint{}, nil
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal([]byte(raw), &cp); err != nil {
+		// a corrupt checkpoint is not worth failing the migration over; start fresh.
+		return checkpoint{}, nil
+	}
+	return cp, nil
+}
+
+func (s *checkpointStore) save(ctx context.Context, gr schema.GroupResource, writeKey string, cp checkpoint) error {
+	raw, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		cm, err := s.configMapClient.ConfigMaps(s.namespace).Get(ctx, checkpointConfigMapName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: checkpointConfigMapName, Namespace: s.namespace},
+				Data:       map[string]string{},
+			}
+			cm, err = s.configMapClient.ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{})
+		}
+		if err != nil {
+			return err
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[checkpointKey(gr, writeKey)] = string(raw)
+		_, err = s.configMapClient.ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (s *checkpointStore) clear(ctx context.Context, gr schema.GroupResource, writeKey string) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		cm, err := s.configMapClient.ConfigMaps(s.namespace).Get(ctx, checkpointConfigMapName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := cm.Data[checkpointKey(gr, writeKey)]; !ok {
+			return nil
+		}
+
+		delete(cm.Data, checkpointKey(gr, writeKey))
+		_, err = s.configMapClient.ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,73 @@
+package migrators
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckpointStoreRoundTrip(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := newCheckpointStore(client.CoreV1(), "openshift-config-managed")
+	ctx := context.Background()
+	gr := schema.GroupResource{Group: "example.com", Resource: "widgets"}
+
+	if cp, err := store.load(ctx, gr, "key1"); err != nil || cp != (checkpoint{}) {
+		t.Fatalf("load with no configmap = %+v, %v, want zero value and no error", cp, err)
+	}
+
+	want := checkpoint{Continue: "abc", ResourceVersion: "10", Processed: 3, Total: 7}
+	if err := store.save(ctx, gr, "key1", want); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	got, err := store.load(ctx, gr, "key1")
+	if err != nil {
+		t.Fatalf("load after save: %v", err)
+	}
+	if got != want {
+		t.Fatalf("load after save = %+v, want %+v", got, want)
+	}
+
+	// a checkpoint for a different write key must not be disturbed by key1's save/clear.
+	if err := store.save(ctx, gr, "key2", checkpoint{Processed: 1}); err != nil {
+		t.Fatalf("save other write key: %v", err)
+	}
+
+	// a checkpoint for a different GR must not be disturbed by gr's save/clear.
+	otherGR := schema.GroupResource{Resource: "gadgets"}
+	if err := store.save(ctx, otherGR, "key1", checkpoint{Processed: 1}); err != nil {
+		t.Fatalf("save other gr: %v", err)
+	}
+
+	if err := store.clear(ctx, gr, "key1"); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if cp, err := store.load(ctx, gr, "key1"); err != nil || cp != (checkpoint{}) {
+		t.Fatalf("load after clear = %+v, %v, want zero value and no error", cp, err)
+	}
+	if cp, err := store.load(ctx, gr, "key2"); err != nil || cp.Processed != 1 {
+		t.Fatalf("load other write key after clearing key1 = %+v, %v, want Processed=1", cp, err)
+	}
+	if cp, err := store.load(ctx, otherGR, "key1"); err != nil || cp.Processed != 1 {
+		t.Fatalf("load other gr after clearing gr = %+v, %v, want Processed=1", cp, err)
+	}
+}
+
+func TestCheckpointKey(t *testing.T) {
+	tests := []struct {
+		gr       schema.GroupResource
+		writeKey string
+		want     string
+	}{
+		{schema.GroupResource{Resource: "secrets"}, "key1", "secrets-key1"},
+		{schema.GroupResource{Group: "example.com", Resource: "widgets"}, "key1", "widgets.example.com-key1"},
+	}
+	for _, tt := range tests {
+		if got := checkpointKey(tt.gr, tt.writeKey); got != tt.want {
+			t.Errorf("checkpointKey(%v, %q) = %q, want %q", tt.gr, tt.writeKey, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package migrators
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+)
+
+// connectionErrorBackoff bounds how long migrateAll retries a single list or update call
+// after a generic (non-Gone) connection error, so a flaky apiserver slows a migration down
+// instead of failing it outright and flipping the controller degraded.
+var connectionErrorBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.5,
+	Steps:    6,
+	Cap:      time.Minute,
+}
+
+// InProcessMigrator migrates the storage of a resource to a new write key by listing every
+// object of the GroupResource and writing it back unchanged through dynamicClient, relying on
+// the apiserver to re-encrypt on write. It is the dependency-free Migrator;
+// see StorageVersionMigrator for an implementation backed by the kube-storage-version-migrator.
+//
+// Progress is checkpointed per-GR (see checkpointStore) so that a migration interrupted by an
+// apiserver restart resumes from its last list continuation token instead of starting over.
+type InProcessMigrator struct {
+	dynamicClient dynamic.Interface
+	resourceFor   func(schema.GroupResource) (schema.GroupVersionResource, error)
+	checkpoints   *checkpointStore
+	rateLimiter   flowcontrol.RateLimiter // nil means use the client's own rate limiting
+	sem           chan struct{}           // nil means unbounded concurrency
+
+	mu      sync.Mutex
+	results map[migrationKey]migrationResult
+	// epoch counts how many times EnsureMigration/PruneMigration have (re)started or
+	// abandoned a migration of a given key. Each migrate goroutine captures the epoch
+	// it was launched with and refuses to write m.results/checkpoints once it no longer
+	// matches, so a goroutine left running past a PruneMigration (or a restart) cannot
+	// resurrect a stale result into whatever migration is current for that key.
+	epoch map[migrationKey]uint64
+	// cancel holds the CancelFunc for the migrate goroutine currently running for a key, so
+	// PruneMigration can actually stop the background list/update loop instead of merely
+	// disowning its result.
+	cancel map[migrationKey]context.CancelFunc
+	// done is closed by migrate when the goroutine it was handed to returns, so PruneMigration
+	// can wait for the goroutine to actually exit (and so for any checkpoints.save call it had
+	// already started to complete) before clearing its checkpoint out from under it.
+	done map[migrationKey]chan struct{}
+}
+
+// migrationKey identifies one migration attempt: a GroupResource being migrated to a specific
+// write key. Keying on writeKey as well as gr (mirroring StorageVersionMigrator's migrationName)
+// means a write-key rotation starts a fresh migration instead of reusing a stale, already
+// completed result cached for the GR's previous key.
+type migrationKey struct {
+	gr       schema.GroupResource
+	writeKey string
+}
+
+type migrationResult struct {
+	finished  bool
+	err       error
+	when      time.Time
+	processed int64
+	total     int64
+}
+
+// NewInProcessMigrator returns a Migrator that migrates storage in-process via dynamicClient.
+// resourceFor resolves a GroupResource to the GroupVersionResource the dynamic client should
+// list and update, typically backed by a RESTMapper. checkpointClient/checkpointNamespace
+// (conventionally "openshift-config-managed") back the per-GR progress checkpoint.
+//
+// By default every GR is migrated concurrently with no extra client-side rate limiting beyond
+// whatever dynamicClient was built with; use WithClientRateLimit and WithMaxConcurrentMigrations
+// to tune both for large clusters.
+func NewInProcessMigrator(dynamicClient dynamic.Interface, resourceFor func(schema.GroupResource) (schema.GroupVersionResource, error), checkpointClient corev1client.ConfigMapsGetter, checkpointNamespace string, opts ...Option) *InProcessMigrator {
+	o := newOptions(opts)
+
+	m := &InProcessMigrator{
+		dynamicClient: dynamicClient,
+		resourceFor:   resourceFor,
+		checkpoints:   newCheckpointStore(checkpointClient, checkpointNamespace),
+		results:       map[migrationKey]migrationResult{},
+		epoch:         map[migrationKey]uint64{},
+		cancel:        map[migrationKey]context.CancelFunc{},
+		done:          map[migrationKey]chan struct{}{},
+	}
+	if o.qps > 0 {
+		m.rateLimiter = flowcontrol.NewTokenBucketRateLimiter(o.qps, o.burst)
+	}
+	if o.maxConcurrent > 0 {
+		m.sem = make(chan struct{}, o.maxConcurrent)
+	}
+	return m
+}
+
+func (m *InProcessMigrator) EnsureMigration(gr schema.GroupResource, writeKey string) (finished bool, result error, when time.Time, err error) {
+	key := migrationKey{gr: gr, writeKey: writeKey}
+
+	m.mu.Lock()
+	if res, ok := m.results[key]; ok {
+		m.mu.Unlock()
+		return res.finished, res.err, res.when, nil
+	}
+	// Record an in-progress placeholder before releasing the lock so a concurrent
+	// EnsureMigration call for the same key (a resync, or the status update below
+	// retriggering the informer) observes finished=false instead of racing us to
+	// start a second migrate(key) goroutine.
+	m.epoch[key]++
+	ep := m.epoch[key]
+	m.results[key] = migrationResult{}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel[key] = cancel
+	done := make(chan struct{})
+	m.done[key] = done
+	m.mu.Unlock()
+
+	go m.migrate(ctx, done, key, ep)
+	return false, nil, time.Time{}, nil
+}
+
+func (m *InProcessMigrator) migrate(ctx context.Context, done chan struct{}, key migrationKey, epoch uint64) {
+	defer close(done)
+	if m.sem != nil {
+		m.sem <- struct{}{}
+		defer func() { <-m.sem }()
+	}
+
+	migrationsInFlight.Inc()
+	defer migrationsInFlight.Dec()
+	start := time.Now()
+
+	err := m.migrateAll(ctx, key, epoch)
+
+	duration := time.Since(start)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.epoch[key] != epoch {
+		// key was pruned (or restarted) while we were migrating; this result is stale
+		// and must not be written over whatever migration is current for key now.
+		return
+	}
+	delete(m.cancel, key)
+	delete(m.done, key)
+	migrationLastDurationSeconds.WithLabelValues(metricGR(key.gr)).Set(duration.Seconds())
+	res := m.results[key]
+	if duration > 0 {
+		migrationObjectsPerSecond.WithLabelValues(metricGR(key.gr)).Set(float64(res.processed) / duration.Seconds())
+	}
+	res.finished = true
+	res.err = err
+	res.when = time.Now()
+	m.results[key] = res
+}
+
+// migrateAll lists every object of gr and writes it back unchanged, resuming from the last
+// checkpointed continuation token if one exists. On Expired/Gone list errors it drops the
+// checkpoint and re-lists from scratch; on connection errors (see isConnectionError) it retries
+// with a backoff before giving up and surfacing the error; any other error is surfaced at once.
+func (m *InProcessMigrator) migrateAll(ctx context.Context, key migrationKey, epoch uint64) error {
+	gr := key.gr
+	gvr, err := m.resourceFor(gr)
+	if err != nil {
+		return err
+	}
+
+	cp, err := m.checkpoints.load(ctx, gr, key.writeKey)
+	if err != nil {
+		return fmt.Errorf("failed to load migration checkpoint for %s: %v", gr, err)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			// PruneMigration cancelled us; stop paging through the list instead of
+			// continuing to migrate a GR the controller believes has been stopped.
+			return err
+		}
+
+		var list, listErr = m.listWithBackoff(ctx, gvr, cp)
+		if listErr != nil {
+			if apierrors.IsResourceExpired(listErr) || apierrors.IsGone(listErr) {
+				klog.Warningf("migration list for %s expired, restarting from scratch", gr)
+				cp = checkpoint{}
+				continue
+			}
+			return listErr
+		}
+
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if err := m.updateWithBackoff(ctx, gvr, obj); err != nil {
+				return fmt.Errorf("failed to migrate %s %s/%s: %v", gr.String(), obj.GetNamespace(), obj.GetName(), err)
+			}
+			cp.Processed++
+		}
+
+		if remaining := list.GetRemainingItemCount(); remaining != nil {
+			cp.Total = cp.Processed + *remaining
+		} else {
+			cp.Total = cp.Processed
+		}
+		if len(cp.ResourceVersion) == 0 {
+			cp.ResourceVersion = list.GetResourceVersion()
+		}
+		cp.Continue = list.GetContinue()
+
+		m.mu.Lock()
+		stale := m.epoch[key] != epoch
+		if !stale {
+			res := m.results[key]
+			res.processed, res.total = cp.Processed, cp.Total
+			m.results[key] = res
+		}
+		m.mu.Unlock()
+
+		if !stale {
+			// A PruneMigration that raced us past the processed/total update above
+			// must not have its checkpoints.clear resurrected by a late save.
+			if err := m.checkpoints.save(ctx, gr, key.writeKey, cp); err != nil {
+				klog.Warningf("failed to persist migration checkpoint for %s: %v", gr, err)
+			}
+		}
+
+		if len(cp.Continue) == 0 {
+			break
+		}
+	}
+
+	if err := m.checkpoints.clear(ctx, gr, key.writeKey); err != nil {
+		klog.Warningf("failed to clear migration checkpoint for %s: %v", gr, err)
+	}
+	return nil
+}
+
+func (m *InProcessMigrator) listWithBackoff(ctx context.Context, gvr schema.GroupVersionResource, cp checkpoint) (result *unstructured.UnstructuredList, err error) {
+	opts := metav1.ListOptions{Continue: cp.Continue, Limit: 500}
+	if len(cp.Continue) == 0 {
+		opts.ResourceVersion = cp.ResourceVersion
+	}
+
+	var lastErr error
+	err = wait.ExponentialBackoff(connectionErrorBackoff, func() (bool, error) {
+		m.wait()
+		list, listErr := m.dynamicClient.Resource(gvr).List(ctx, opts)
+		if listErr == nil {
+			result = list
+			return true, nil
+		}
+		lastErr = listErr
+		if apierrors.IsResourceExpired(listErr) || apierrors.IsGone(listErr) {
+			return false, listErr // not retryable here, let the caller restart from scratch
+		}
+		if isConnectionError(listErr) {
+			return false, nil // retry with backoff
+		}
+		return false, listErr
+	})
+	if err == wait.ErrWaitTimeout && lastErr != nil {
+		// the backoff was exhausted by retryable connection errors; surface the last one
+		// instead of the generic timeout so operators can see what actually went wrong.
+		err = lastErr
+	}
+	return result, err
+}
+
+func (m *InProcessMigrator) updateWithBackoff(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	var lastErr error
+	err := wait.ExponentialBackoff(connectionErrorBackoff, func() (bool, error) {
+		m.wait()
+		_, err := m.dynamicClient.Resource(gvr).Namespace(obj.GetNamespace()).Update(ctx, obj, metav1.UpdateOptions{})
+		if err == nil {
+			return true, nil
+		}
+		lastErr = err
+		if isConnectionError(err) {
+			return false, nil // retry with backoff
+		}
+		return false, err
+	})
+	if err == wait.ErrWaitTimeout && lastErr != nil {
+		// the backoff was exhausted by retryable connection errors; surface the last one
+		// instead of the generic timeout so operators can see what actually went wrong.
+		err = lastErr
+	}
+	return err
+}
+
+// PruneMigration stops and removes every migration tracked for gr, regardless of which write
+// key it targeted: the interface doesn't carry a writeKey here, and the upstream-mirroring
+// StorageVersionMigrator.PruneMigration likewise looks at every SVM for gr whatever its write key
+// (though it keeps succeeded ones).
+func (m *InProcessMigrator) PruneMigration(gr schema.GroupResource) error {
+	m.mu.Lock()
+	var writeKeys []string
+	var pending []chan struct{}
+	for key := range m.results {
+		if key.gr != gr {
+			continue
+		}
+		delete(m.results, key)
+		// Bump the epoch so a migrate goroutine started before this prune (and still
+		// running) discards its result instead of resurrecting it once it finishes.
+		m.epoch[key]++
+		// Cancel the in-flight migrate goroutine for key, if any, so the background
+		// list/update loop actually stops instead of running to completion unobserved.
+		if cancel, ok := m.cancel[key]; ok {
+			cancel()
+			delete(m.cancel, key)
+		}
+		if done, ok := m.done[key]; ok {
+			pending = append(pending, done)
+			delete(m.done, key)
+		}
+		writeKeys = append(writeKeys, key.writeKey)
+	}
+	m.mu.Unlock()
+
+	// Wait for every cancelled migrate goroutine to actually exit before clearing its
+	// checkpoint: migrate only returns after any checkpoints.save call it already had in
+	// flight completes, so waiting for it here guarantees clear() below can't be raced by a
+	// save() that resurrects the checkpoint it's supposed to remove.
+	for _, done := range pending {
+		<-done
+	}
+
+	var errs []error
+	for _, writeKey := range writeKeys {
+		if err := m.checkpoints.clear(context.Background(), gr, writeKey); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+func (m *InProcessMigrator) Progress(gr schema.GroupResource) (processed, total int64, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var best migrationResult
+	var bestEpoch uint64
+	found := false
+	for key, res := range m.results {
+		if key.gr != gr {
+			continue
+		}
+		if ep := m.epoch[key]; !found || ep > bestEpoch {
+			best, bestEpoch, found = res, ep, true
+		}
+	}
+	if !found {
+		return 0, 0, false
+	}
+	return best.processed, best.total, true
+}
+
+func (m *InProcessMigrator) Run(stopCh <-chan struct{}) {}
+
+func (m *InProcessMigrator) HasSynced() bool { return true }
+
+func (m *InProcessMigrator) LastSyncResourceVersion() string { return "" }
+
+// wait blocks until the configured client rate limit (see WithClientRateLimit) permits another
+// API call. It is a no-op when no rate limit was configured.
+func (m *InProcessMigrator) wait() {
+	if m.rateLimiter != nil {
+		m.rateLimiter.Accept()
+	}
+}
+
+// isConnectionError reports whether err looks like a transient failure to reach the apiserver
+// (timeouts, connection resets, ...) as opposed to a terminal rejection of the request, so that
+// migrateAll can retry it with backoff instead of failing the migration outright.
+func isConnectionError(err error) bool {
+	return net.IsConnectionReset(err) || net.IsConnectionRefused(err) ||
+		apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) || apierrors.IsTooManyRequests(err)
+}
+
+var _ Migrator = &InProcessMigrator{}
@@ -0,0 +1,272 @@
+package migrators
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	fakecore "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var (
+	testGR  = schema.GroupResource{Group: "example.com", Resource: "widgets"}
+	testGVR = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+)
+
+func newTestMigrator(t *testing.T, objs ...runtime.Object) (*InProcessMigrator, *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+		map[schema.GroupVersionResource]string{testGVR: "WidgetList"}, objs...)
+
+	resourceFor := func(gr schema.GroupResource) (schema.GroupVersionResource, error) {
+		return testGVR, nil
+	}
+
+	m := NewInProcessMigrator(dynamicClient, resourceFor, fakecore.NewSimpleClientset().CoreV1(), "openshift-config-managed")
+	return m, dynamicClient
+}
+
+func newWidget(name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("example.com/v1")
+	obj.SetKind("Widget")
+	obj.SetName(name)
+	return obj
+}
+
+// waitForFinished polls m's result for gr until EnsureMigration reports finished, or fails the
+// test after a generous timeout; the fake dynamic client has no real network latency so this
+// should resolve almost immediately once the migrate goroutine is unblocked.
+func waitForFinished(t *testing.T, m *InProcessMigrator, gr schema.GroupResource) (bool, error) {
+	t.Helper()
+	return waitForFinishedWithKey(t, m, gr, "key1")
+}
+
+// waitForFinishedWithKey is waitForFinished for a caller-chosen write key.
+func waitForFinishedWithKey(t *testing.T, m *InProcessMigrator, gr schema.GroupResource, writeKey string) (bool, error) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		finished, result, _, err := m.EnsureMigration(gr, writeKey)
+		if err != nil {
+			t.Fatalf("EnsureMigration: %v", err)
+		}
+		if finished {
+			return finished, result
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("migration for %s did not finish in time", gr)
+	return false, nil
+}
+
+func TestEnsureMigrationCompletes(t *testing.T) {
+	m, _ := newTestMigrator(t, newWidget("a"), newWidget("b"))
+
+	finished, _, _, err := m.EnsureMigration(testGR, "key1")
+	if err != nil {
+		t.Fatalf("EnsureMigration: %v", err)
+	}
+	if finished {
+		t.Fatalf("finished = true on first call, want false (migration just started)")
+	}
+
+	if finished, result := waitForFinished(t, m, testGR); !finished || result != nil {
+		t.Fatalf("final EnsureMigration = finished=%v, result=%v, want finished=true, result=nil", finished, result)
+	}
+
+	if processed, total, ok := m.Progress(testGR); !ok || processed != 2 || total != 2 {
+		t.Fatalf("Progress = %d/%d, ok=%v, want 2/2, ok=true", processed, total, ok)
+	}
+}
+
+// TestEnsureMigrationDoesNotDuplicateInFlightMigration reproduces the race a second
+// EnsureMigration call used to hit: it blocks the first migration's list call, calls
+// EnsureMigration again while that first call is still outstanding, and asserts the second
+// call neither reports "not found" (spawning a competing migrate goroutine) nor causes a
+// second List against the apiserver.
+func TestEnsureMigrationDoesNotDuplicateInFlightMigration(t *testing.T) {
+	m, dynamicClient := newTestMigrator(t, newWidget("a"))
+
+	var listCount int32
+	release := make(chan struct{})
+	dynamicClient.PrependReactor("list", "widgets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt32(&listCount, 1)
+		<-release
+		return false, nil, nil // let the default reactor still perform the list
+	})
+
+	finished, _, _, err := m.EnsureMigration(testGR, "key1")
+	if err != nil {
+		t.Fatalf("first EnsureMigration: %v", err)
+	}
+	if finished {
+		t.Fatalf("finished = true on first call, want false")
+	}
+
+	// give the migrate goroutine a moment to reach the blocked List call.
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&listCount) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	finished, result, _, err := m.EnsureMigration(testGR, "key1")
+	if err != nil {
+		t.Fatalf("second EnsureMigration: %v", err)
+	}
+	if finished {
+		t.Fatalf("second EnsureMigration finished=true, result=%v, want false: it must observe the in-flight placeholder, not start a second migration", result)
+	}
+
+	close(release)
+
+	waitForFinished(t, m, testGR)
+
+	if got := atomic.LoadInt32(&listCount); got != 1 {
+		t.Fatalf("List was called %d times, want exactly 1: a concurrent EnsureMigration spawned a duplicate migrate goroutine", got)
+	}
+}
+
+// TestEnsureMigrationRotatesWriteKey reproduces what a write-key rotation without an
+// intervening PruneMigration would do: a stale, completed result cached for the GR's previous
+// write key must not be returned for a new write key, or the new key would be marked migrated
+// without ever having an object written under it.
+func TestEnsureMigrationRotatesWriteKey(t *testing.T) {
+	m, _ := newTestMigrator(t, newWidget("a"))
+
+	finished, result, _, err := m.EnsureMigration(testGR, "keyA")
+	if err != nil {
+		t.Fatalf("EnsureMigration(keyA): %v", err)
+	}
+	if finished {
+		t.Fatalf("finished = true on first call, want false")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if finished, result, _, err = m.EnsureMigration(testGR, "keyA"); err != nil {
+			t.Fatalf("EnsureMigration(keyA): %v", err)
+		}
+		if finished {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !finished || result != nil {
+		t.Fatalf("migration for keyA did not finish cleanly: finished=%v, result=%v", finished, result)
+	}
+
+	finished, result, _, err = m.EnsureMigration(testGR, "keyB")
+	if err != nil {
+		t.Fatalf("EnsureMigration(keyB): %v", err)
+	}
+	if finished {
+		t.Fatalf("EnsureMigration(keyB) finished=true, result=%v immediately after keyA completed: it must start its own migration, not reuse keyA's cached result", result)
+	}
+
+	if finished, result := waitForFinishedWithKey(t, m, testGR, "keyB"); !finished || result != nil {
+		t.Fatalf("final EnsureMigration(keyB) = finished=%v, result=%v, want finished=true, result=nil", finished, result)
+	}
+}
+
+// TestPruneMigrationDiscardsStaleResult reproduces the second half of the same race: a
+// migrate goroutine still running when PruneMigration is called must not resurrect its
+// result into m.results once it eventually completes. PruneMigration now also blocks until
+// that goroutine actually exits (see TestPruneMigrationWaitsForMigrateGoroutine), so this test
+// unblocks it from a second goroutine instead of racing PruneMigration's own return.
+func TestPruneMigrationDiscardsStaleResult(t *testing.T) {
+	m, dynamicClient := newTestMigrator(t, newWidget("a"))
+
+	release := make(chan struct{})
+	unblocked := make(chan struct{})
+	dynamicClient.PrependReactor("list", "widgets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		close(unblocked)
+		<-release
+		return false, nil, nil
+	})
+
+	if _, _, _, err := m.EnsureMigration(testGR, "key1"); err != nil {
+		t.Fatalf("EnsureMigration: %v", err)
+	}
+
+	select {
+	case <-unblocked:
+	case <-time.After(time.Second):
+		t.Fatalf("migrate goroutine never reached the blocked List call")
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+
+	if err := m.PruneMigration(testGR); err != nil {
+		t.Fatalf("PruneMigration: %v", err)
+	}
+	if _, _, ok := m.Progress(testGR); ok {
+		t.Fatalf("Progress reports a tracked migration immediately after PruneMigration")
+	}
+
+	// give the now-abandoned migrate goroutine a moment past its own exit to attempt to
+	// write its result; PruneMigration having already returned, it never should.
+	time.Sleep(50 * time.Millisecond)
+
+	if _, _, ok := m.Progress(testGR); ok {
+		t.Fatalf("a migrate goroutine started before PruneMigration resurrected a result afterwards")
+	}
+}
+
+// TestPruneMigrationWaitsForMigrateGoroutine reproduces the race the "don't resurrect a pruned
+// migration's checkpoint" fix left open: PruneMigration must not clear a GR's checkpoint until
+// the migrate goroutine it cancelled has actually returned, because that goroutine may already
+// be completing a checkpoints.save call that would otherwise land after the clear and
+// resurrect progress for a migration the controller believes it stopped.
+func TestPruneMigrationWaitsForMigrateGoroutine(t *testing.T) {
+	m, dynamicClient := newTestMigrator(t, newWidget("a"))
+
+	release := make(chan struct{})
+	unblocked := make(chan struct{})
+	dynamicClient.PrependReactor("list", "widgets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		close(unblocked)
+		<-release
+		return false, nil, nil
+	})
+
+	if _, _, _, err := m.EnsureMigration(testGR, "key1"); err != nil {
+		t.Fatalf("EnsureMigration: %v", err)
+	}
+
+	select {
+	case <-unblocked:
+	case <-time.After(time.Second):
+		t.Fatalf("migrate goroutine never reached the blocked List call")
+	}
+
+	pruneDone := make(chan error, 1)
+	go func() { pruneDone <- m.PruneMigration(testGR) }()
+
+	select {
+	case err := <-pruneDone:
+		t.Fatalf("PruneMigration returned (err=%v) before its cancelled migrate goroutine exited", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case err := <-pruneDone:
+		if err != nil {
+			t.Fatalf("PruneMigration: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("PruneMigration did not return after its migrate goroutine was unblocked")
+	}
+}
@@ -0,0 +1,35 @@
+package migrators
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Migrator abstracts the mechanics of migrating the storage of a GroupResource to a new
+// write key. migrationController only decides when a migration is necessary; a Migrator
+// implementation is responsible for starting, tracking and cleaning up the migration itself.
+type Migrator interface {
+	// Migrator is passed to factory.Controller.WithInformers so that changes to whatever
+	// backs the migration (a job, a CR, ...) trigger a resync of migrationController.
+	cache.Controller
+
+	// EnsureMigration makes sure that a migration of gr to writeKey is either running, or has
+	// already run to completion. finished indicates whether the migration has reached a
+	// terminal state; result is the error the migration terminated with (nil on success) and
+	// is only meaningful when finished is true. when is the time the migration reached that
+	// terminal state. err is returned when the migration could not be started or observed and
+	// never represents a terminal migration failure.
+	EnsureMigration(gr schema.GroupResource, writeKey string) (finished bool, result error, when time.Time, err error)
+
+	// PruneMigration stops and removes any migration state tracked for gr, for example
+	// because the target encryption config changed before the migration finished.
+	PruneMigration(gr schema.GroupResource) error
+
+	// Progress reports how many objects have been migrated so far for gr, and the estimated
+	// total, so callers can surface forward progress across apiserver restarts. ok is false
+	// when no migration is currently tracked for gr, or the implementation cannot estimate
+	// progress, in which case processed and total must be ignored.
+	Progress(gr schema.GroupResource) (processed, total int64, ok bool)
+}
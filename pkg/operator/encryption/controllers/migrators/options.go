@@ -0,0 +1,42 @@
+package migrators
+
+// Option configures optional, tunable behavior shared by the Migrator constructors in this
+// package. The zero value of each underlying setting means "leave the client/implementation
+// default in place".
+type Option func(*options)
+
+type options struct {
+	qps           float32
+	burst         int
+	maxConcurrent int
+}
+
+func newOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClientRateLimit puts a qps/burst token-bucket limit on the migrator's own API calls (listing
+// and writing back objects, or creating/polling StorageVersionMigrations). The limit applies in
+// addition to whatever rate limits the client passed into the constructor was built with; it
+// does not replace them, so it cannot raise the request rate above that client's own limit.
+// The upstream kube-storage-version-migrator ships with --kube-api-qps=40 --kube-api-burst=1000
+// because the client-go defaults are too conservative for large clusters.
+func WithClientRateLimit(qps float32, burst int) Option {
+	return func(o *options) {
+		o.qps = qps
+		o.burst = burst
+	}
+}
+
+// WithMaxConcurrentMigrations bounds how many GroupResources a Migrator will migrate at the
+// same time. The limiter is shared across all GroupResources so that a single large or noisy
+// resource cannot starve the others of API request budget. n <= 0 means unbounded.
+func WithMaxConcurrentMigrations(n int) Option {
+	return func(o *options) {
+		o.maxConcurrent = n
+	}
+}
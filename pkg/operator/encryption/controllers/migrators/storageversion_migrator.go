@@ -0,0 +1,151 @@
+package migrators
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
+
+	migrationv1alpha1 "github.com/openshift/kube-storage-version-migrator/pkg/apis/migration/v1alpha1"
+	migrationv1alpha1client "github.com/openshift/kube-storage-version-migrator/pkg/clients/clientset/typed/migration/v1alpha1"
+)
+
+// StorageVersionMigrator drives migration by creating and reconciling StorageVersionMigration
+// (SVM) objects, which are picked up and run by the kube-storage-version-migrator, instead of
+// migrating resources in-process. It creates one SVM per GroupResource/write-key pair so that
+// rotating the write key starts a fresh migration rather than re-observing a stale one.
+type StorageVersionMigrator struct {
+	client      migrationv1alpha1client.StorageVersionMigrationsGetter
+	informer    cache.SharedIndexInformer
+	rateLimiter flowcontrol.RateLimiter // nil means use the client's own rate limiting
+}
+
+// NewStorageVersionMigrator returns a Migrator that manages StorageVersionMigration objects
+// through client. informer must be an informer on StorageVersionMigrations and is only used to
+// satisfy the cache.Controller contract so that SVM status updates resync migrationController.
+//
+// WithClientRateLimit can be used to put a QPS/burst limit on the SVM create/get/list/delete
+// calls this migrator makes, on top of whatever rate limits the client passed in already has.
+// WithMaxConcurrentMigrations has no effect here: concurrency of the actual migration work is
+// controlled by the kube-storage-version-migrator reconciling the SVMs, not by this client.
+func NewStorageVersionMigrator(client migrationv1alpha1client.StorageVersionMigrationsGetter, informer cache.SharedIndexInformer, opts ...Option) *StorageVersionMigrator {
+	o := newOptions(opts)
+
+	m := &StorageVersionMigrator{
+		client:   client,
+		informer: informer,
+	}
+	if o.qps > 0 {
+		m.rateLimiter = flowcontrol.NewTokenBucketRateLimiter(o.qps, o.burst)
+	}
+	return m
+}
+
+func (m *StorageVersionMigrator) wait() {
+	if m.rateLimiter != nil {
+		m.rateLimiter.Accept()
+	}
+}
+
+// migrationName derives a deterministic SVM name from gr and the write key that triggered the
+// migration, so re-creation of the write key (e.g. a key rotation) produces a new SVM instead
+// of reusing one that already ran to completion for a previous key.
+func migrationName(gr schema.GroupResource, writeKey string) string {
+	if len(gr.Group) == 0 {
+		return fmt.Sprintf("encryption-%s-%s", gr.Resource, writeKey)
+	}
+	return fmt.Sprintf("encryption-%s-%s-%s", gr.Resource, gr.Group, writeKey)
+}
+
+func (m *StorageVersionMigrator) EnsureMigration(gr schema.GroupResource, writeKey string) (finished bool, result error, when time.Time, err error) {
+	name := migrationName(gr, writeKey)
+
+	m.wait()
+	svm, err := m.client.StorageVersionMigrations().Get(context.TODO(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		svm = &migrationv1alpha1.StorageVersionMigration{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: migrationv1alpha1.StorageVersionMigrationSpec{
+				Resource: migrationv1alpha1.GroupVersionResource{
+					Group:    gr.Group,
+					Resource: gr.Resource,
+				},
+			},
+		}
+		m.wait()
+		if svm, err = m.client.StorageVersionMigrations().Create(context.TODO(), svm, metav1.CreateOptions{}); err != nil {
+			return false, nil, time.Time{}, fmt.Errorf("failed to create StorageVersionMigration %q for %s: %v", name, gr, err)
+		}
+	} else if err != nil {
+		return false, nil, time.Time{}, fmt.Errorf("failed to get StorageVersionMigration %q for %s: %v", name, gr, err)
+	}
+
+	finished, result, when = svmResult(svm)
+	return finished, result, when, nil
+}
+
+// svmResult translates an SVM's status conditions into the Migrator (finished, result, when)
+// contract. A missing or all-false set of conditions means the migration is still running.
+func svmResult(svm *migrationv1alpha1.StorageVersionMigration) (finished bool, result error, when time.Time) {
+	for _, c := range svm.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case migrationv1alpha1.MigrationSucceeded:
+			return true, nil, c.LastUpdateTime.Time
+		case migrationv1alpha1.MigrationFailed:
+			return true, fmt.Errorf("StorageVersionMigration %q failed: %s", svm.Name, c.Message), c.LastUpdateTime.Time
+		}
+	}
+	return false, nil, time.Time{}
+}
+
+func (m *StorageVersionMigrator) PruneMigration(gr schema.GroupResource) error {
+	m.wait()
+	list, err := m.client.StorageVersionMigrations().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range list.Items {
+		svm := &list.Items[i]
+		if svm.Spec.Resource.Group != gr.Group || svm.Spec.Resource.Resource != gr.Resource {
+			continue
+		}
+		if finished, result, _ := svmResult(svm); finished && result == nil {
+			continue // leave the succeeded run so EnsureMigration keeps observing it
+		}
+
+		// pending, running or failed: remove it so the next EnsureMigration starts fresh
+		m.wait()
+		if err := m.client.StorageVersionMigrations().Delete(context.TODO(), svm.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete StorageVersionMigration %q: %v", svm.Name, err))
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// Progress is not supported: StorageVersionMigration's status does not expose an
+// objects-processed count, only terminal conditions.
+func (m *StorageVersionMigrator) Progress(gr schema.GroupResource) (processed, total int64, ok bool) {
+	return 0, 0, false
+}
+
+func (m *StorageVersionMigrator) Run(stopCh <-chan struct{}) { m.informer.Run(stopCh) }
+
+func (m *StorageVersionMigrator) HasSynced() bool { return m.informer.HasSynced() }
+
+func (m *StorageVersionMigrator) LastSyncResourceVersion() string {
+	return m.informer.LastSyncResourceVersion()
+}
+
+var _ Migrator = &StorageVersionMigrator{}
@@ -0,0 +1,229 @@
+package migrators
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	migrationv1alpha1 "github.com/openshift/kube-storage-version-migrator/pkg/apis/migration/v1alpha1"
+	migrationv1alpha1fake "github.com/openshift/kube-storage-version-migrator/pkg/clients/clientset/fake"
+)
+
+func TestMigrationName(t *testing.T) {
+	tests := []struct {
+		name     string
+		gr       schema.GroupResource
+		writeKey string
+		want     string
+	}{
+		{"no group", schema.GroupResource{Resource: "secrets"}, "key1", "encryption-secrets-key1"},
+		{"with group", schema.GroupResource{Group: "example.com", Resource: "widgets"}, "key1", "encryption-widgets-example.com-key1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := migrationName(tt.gr, tt.writeKey); got != tt.want {
+				t.Errorf("migrationName(%v, %q) = %q, want %q", tt.gr, tt.writeKey, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSVMResult(t *testing.T) {
+	now := metav1.NewTime(time.Unix(1234, 0))
+
+	tests := []struct {
+		name          string
+		conditions    []migrationv1alpha1.MigrationCondition
+		wantFinished  bool
+		wantErr       bool
+		wantErrSubstr string
+	}{
+		{
+			name:         "no conditions",
+			conditions:   nil,
+			wantFinished: false,
+		},
+		{
+			name: "unrelated condition true",
+			conditions: []migrationv1alpha1.MigrationCondition{
+				{Type: "SomeOtherCondition", Status: corev1.ConditionTrue, LastUpdateTime: now},
+			},
+			wantFinished: false,
+		},
+		{
+			name: "succeeded",
+			conditions: []migrationv1alpha1.MigrationCondition{
+				{Type: migrationv1alpha1.MigrationSucceeded, Status: corev1.ConditionTrue, LastUpdateTime: now},
+			},
+			wantFinished: true,
+		},
+		{
+			name: "failed",
+			conditions: []migrationv1alpha1.MigrationCondition{
+				{Type: migrationv1alpha1.MigrationFailed, Status: corev1.ConditionTrue, Message: "boom", LastUpdateTime: now},
+			},
+			wantFinished:  true,
+			wantErr:       true,
+			wantErrSubstr: "boom",
+		},
+		{
+			name: "false conditions are ignored",
+			conditions: []migrationv1alpha1.MigrationCondition{
+				{Type: migrationv1alpha1.MigrationSucceeded, Status: corev1.ConditionFalse, LastUpdateTime: now},
+				{Type: migrationv1alpha1.MigrationFailed, Status: corev1.ConditionFalse, LastUpdateTime: now},
+			},
+			wantFinished: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svm := &migrationv1alpha1.StorageVersionMigration{
+				ObjectMeta: metav1.ObjectMeta{Name: "encryption-widgets-key1"},
+				Status:     migrationv1alpha1.StorageVersionMigrationStatus{Conditions: tt.conditions},
+			}
+			finished, err, when := svmResult(svm)
+			if finished != tt.wantFinished {
+				t.Errorf("finished = %v, want %v", finished, tt.wantFinished)
+			}
+			if tt.wantErr && err == nil {
+				t.Fatalf("err = nil, want error containing %q", tt.wantErrSubstr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("err = %v, want nil", err)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.wantErrSubstr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErrSubstr)
+			}
+			if tt.wantFinished && when.IsZero() {
+				t.Errorf("when is zero, want the condition's LastUpdateTime")
+			}
+		})
+	}
+}
+
+func TestStorageVersionMigratorEnsureMigrationCreatesOnNotFound(t *testing.T) {
+	client := migrationv1alpha1fake.NewSimpleClientset()
+	m := NewStorageVersionMigrator(client.MigrationV1alpha1(), nil)
+
+	finished, result, _, err := m.EnsureMigration(testGR, "key1")
+	if err != nil {
+		t.Fatalf("EnsureMigration: %v", err)
+	}
+	if finished {
+		t.Fatalf("finished = true, want false: a freshly-created SVM has no terminal condition yet")
+	}
+	if result != nil {
+		t.Fatalf("result = %v, want nil", result)
+	}
+
+	name := migrationName(testGR, "key1")
+	svm, err := client.MigrationV1alpha1().StorageVersionMigrations().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("the SVM was not created: %v", err)
+	}
+	if svm.Spec.Resource.Group != testGR.Group || svm.Spec.Resource.Resource != testGR.Resource {
+		t.Fatalf("svm.Spec.Resource = %+v, want %+v", svm.Spec.Resource, testGR)
+	}
+}
+
+func TestStorageVersionMigratorEnsureMigrationIsIdempotent(t *testing.T) {
+	name := migrationName(testGR, "key1")
+	now := metav1.NewTime(time.Unix(1234, 0))
+	existing := &migrationv1alpha1.StorageVersionMigration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: migrationv1alpha1.StorageVersionMigrationSpec{
+			Resource: migrationv1alpha1.GroupVersionResource{Group: testGR.Group, Resource: testGR.Resource},
+		},
+		Status: migrationv1alpha1.StorageVersionMigrationStatus{
+			Conditions: []migrationv1alpha1.MigrationCondition{
+				{Type: migrationv1alpha1.MigrationSucceeded, Status: corev1.ConditionTrue, LastUpdateTime: now},
+			},
+		},
+	}
+	client := migrationv1alpha1fake.NewSimpleClientset(existing)
+	m := NewStorageVersionMigrator(client.MigrationV1alpha1(), nil)
+
+	finished, result, when, err := m.EnsureMigration(testGR, "key1")
+	if err != nil {
+		t.Fatalf("EnsureMigration: %v", err)
+	}
+	if !finished || result != nil {
+		t.Fatalf("finished, result = %v, %v, want true, nil: an already-succeeded SVM should be observed as-is", finished, result)
+	}
+	if !when.Equal(now.Time) {
+		t.Fatalf("when = %v, want %v", when, now.Time)
+	}
+
+	// EnsureMigration must not re-create or otherwise mutate an SVM it already observed.
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "create" || action.GetVerb() == "update" {
+			t.Fatalf("unexpected %s action on an already-existing SVM: %v", action.GetVerb(), action)
+		}
+	}
+}
+
+func TestStorageVersionMigratorPruneMigration(t *testing.T) {
+	otherGR := schema.GroupResource{Group: "example.com", Resource: "gadgets"}
+	now := metav1.NewTime(time.Unix(1234, 0))
+
+	running := &migrationv1alpha1.StorageVersionMigration{
+		ObjectMeta: metav1.ObjectMeta{Name: migrationName(testGR, "key1")},
+		Spec: migrationv1alpha1.StorageVersionMigrationSpec{
+			Resource: migrationv1alpha1.GroupVersionResource{Group: testGR.Group, Resource: testGR.Resource},
+		},
+	}
+	failed := &migrationv1alpha1.StorageVersionMigration{
+		ObjectMeta: metav1.ObjectMeta{Name: migrationName(testGR, "key0")},
+		Spec: migrationv1alpha1.StorageVersionMigrationSpec{
+			Resource: migrationv1alpha1.GroupVersionResource{Group: testGR.Group, Resource: testGR.Resource},
+		},
+		Status: migrationv1alpha1.StorageVersionMigrationStatus{
+			Conditions: []migrationv1alpha1.MigrationCondition{
+				{Type: migrationv1alpha1.MigrationFailed, Status: corev1.ConditionTrue, LastUpdateTime: now},
+			},
+		},
+	}
+	succeeded := &migrationv1alpha1.StorageVersionMigration{
+		ObjectMeta: metav1.ObjectMeta{Name: migrationName(testGR, "key-1")},
+		Spec: migrationv1alpha1.StorageVersionMigrationSpec{
+			Resource: migrationv1alpha1.GroupVersionResource{Group: testGR.Group, Resource: testGR.Resource},
+		},
+		Status: migrationv1alpha1.StorageVersionMigrationStatus{
+			Conditions: []migrationv1alpha1.MigrationCondition{
+				{Type: migrationv1alpha1.MigrationSucceeded, Status: corev1.ConditionTrue, LastUpdateTime: now},
+			},
+		},
+	}
+	otherGRRunning := &migrationv1alpha1.StorageVersionMigration{
+		ObjectMeta: metav1.ObjectMeta{Name: migrationName(otherGR, "key1")},
+		Spec: migrationv1alpha1.StorageVersionMigrationSpec{
+			Resource: migrationv1alpha1.GroupVersionResource{Group: otherGR.Group, Resource: otherGR.Resource},
+		},
+	}
+
+	client := migrationv1alpha1fake.NewSimpleClientset(running, failed, succeeded, otherGRRunning)
+	m := NewStorageVersionMigrator(client.MigrationV1alpha1(), nil)
+
+	if err := m.PruneMigration(testGR); err != nil {
+		t.Fatalf("PruneMigration: %v", err)
+	}
+
+	for _, deleted := range []*migrationv1alpha1.StorageVersionMigration{running, failed} {
+		if _, err := client.MigrationV1alpha1().StorageVersionMigrations().Get(context.TODO(), deleted.Name, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+			t.Errorf("SVM %q still exists after PruneMigration, want it deleted (err=%v)", deleted.Name, err)
+		}
+	}
+	if _, err := client.MigrationV1alpha1().StorageVersionMigrations().Get(context.TODO(), succeeded.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("succeeded SVM %q was deleted, want it kept so EnsureMigration keeps observing it: %v", succeeded.Name, err)
+	}
+	if _, err := client.MigrationV1alpha1().StorageVersionMigrations().Get(context.TODO(), otherGRRunning.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("unrelated GR's SVM %q was deleted, want it untouched: %v", otherGRRunning.Name, err)
+	}
+}